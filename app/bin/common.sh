@@ -0,0 +1,17 @@
+#!/bin/bash
+# Helpers shared by the manpage maintenance scripts, sourced rather than run.
+
+# Symlinks come from package contents and .so requests, neither of which is
+# trusted: only allow relative targets that resolve inside the given tree.
+is_safe_link() {
+	local link="$1"
+	local target="$2"
+	local root="$3"
+	case "$target" in
+	/*) return 1 ;;
+	esac
+	case "$(realpath -m "$(dirname "$link")/$target")" in
+	"$(realpath -m "$root")"/*) return 0 ;;
+	esac
+	return 1
+}
@@ -26,6 +26,9 @@
 # License can be found in /usr/share/common-licenses/GPL-3
 ###############################################################################
 
+# shellcheck source-path=SCRIPTDIR source=common.sh
+source "$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)/common.sh"
+
 CONFIG="${MANPAGES_CONFIG_FILE:-/app/www/config.json}"
 if [[ -z "$CONFIG" ]]; then
 	echo "ERROR: Configuration file not found. Please set \$MANPAGES_CONFIG_FILE."
@@ -35,7 +38,14 @@ fi
 PUBLIC_HTML_DIR="$(jq -r '.public_html_dir' "$CONFIG")"
 
 TEMPDIR=$(mktemp -d -t manpages-fetch-XXXXXX)
-trap 'rm -rf $TEMPDIR 2>/dev/null || true' EXIT HUP INT QUIT TERM
+
+# In the case of freakish package permissions, fix them on rm failure. Any
+# directory still left behind is swept by make-manpage-repo.sh later on.
+cleanup() {
+	rm -rf "$TEMPDIR" 2>/dev/null || (chmod -R 700 "$TEMPDIR" && rm -rf "$TEMPDIR") ||
+		echo "WARN ($(date '+%H:%M:%S.%N')) - ${DIST}: failed to remove [$TEMPDIR]"
+}
+trap cleanup EXIT HUP INT QUIT TERM
 
 DIST="$1"
 PKGURL="$2"
@@ -69,7 +79,11 @@ fi
 
 src_pkg=$(dpkg -I "$DEB" | grep -E "^ Package: |^ Source: " | tail -n1 | sed "s/^.*: //")
 
-dpkg-deb -x "$DEB" "$TEMPDIR"
+# Only extract the manpages rather than the whole package, so the space used
+# is bounded by the manpages of one package, whatever its size (firmware,
+# documentation, ...). With one package at a time per release, that bounds
+# the extracted size of a whole update without needing a separate quota.
+dpkg-deb --fsys-tarfile "$DEB" | tar -x -C "$TEMPDIR" --wildcards "./usr/share*/man/*"
 for i in $man; do
 	#printf "%s\n" "DEBUG: Considering entry [$i]"
 	i=$(printf "%s" "$i" | sed "s/^.*\.\///")
@@ -93,11 +107,19 @@ for i in $man; do
 	outgz=$(dirname "$DESTDIRGZ"/"$i")
 	mkdir -p "$(dirname "$out")" "$outgz" >/dev/null || true
 	if [ "$SYMLINK" = "1" ]; then
+		if ! is_safe_link "$out" "$symlink_src_html" "$DESTDIR"; then
+			printf "%s\n" "WARN ($(date '+%H:%M:%S.%N')) - ${DIST}: Rejected symlink [$out] -> [$symlink_src_html] in [$PKG]"
+			continue
+		fi
 		ln -f -s "$symlink_src_html" "$out"
 		printf "%s\n" "INFO ($(date '+%H:%M:%S.%N')) - ${DIST}: Created symlink [$out]"
 	else
 		if LN=$(zcat "$manpage" | head -n1 | grep "^\.so "); then
 			LN=$(printf "%s" "$LN" | sed -e 's/^\.so /\.\.\//' -e 's/\/\.\.\//\//g' -e 's/$/\.html/')
+			if ! is_safe_link "$out" "$LN" "$DESTDIR"; then
+				printf "%s\n" "WARN ($(date '+%H:%M:%S.%N')) - ${DIST}: Rejected symlink [$out] -> [$LN] in [$PKG]"
+				continue
+			fi
 			ln -f -s "$LN" "$out"
 			printf "INFO ($(date '+%H:%M:%S.%N')) - ${DIST}: Created symlink [%s]" "$out"
 		else
@@ -107,11 +129,32 @@ for i in $man; do
 			TITLE=$(printf "%s" "$BODY" | head -n2 | tail -n1 | sed "s/<[^>]\+>//g")
 			BIN_PKG=$(printf "%s" "$NAME_AND_VER" | sed s/_.*$//g)
 			PKG_LINK="https://launchpad.net/ubuntu/$DIST/+package/$BIN_PKG"
-			BUG_LINK="https://bugs.launchpad.net/ubuntu/+source/$src_pkg/+filebug-advanced"
-			echo "<!--#include virtual='/above1.html' -->
+			# Prefill the bug forms, content bugs go to the package while
+			# rendering problems go to the operator project.
+			PAGE="$(basename "$i" | sed 's/\.\([^.]*\)$/(\1)/')"
+			# The page URL in the rendering issue starts with the site URL, which
+			# nginx fills in when the page is served.
+			{
+				read -r BUG_TITLE
+				read -r RENDER_BUG_TITLE
+				read -r RENDER_BUG_PATH
+			} < <(jq -rn --arg page "$PAGE" --arg dist "$DIST" --arg path "/manpages/$DIST/$i.html" \
+				'"\($page) manpage in \($dist): ", "Rendering issue in \($page) (\($dist))", $path | @uri')
+			BUG_LINK="https://bugs.launchpad.net/ubuntu/+source/$src_pkg/+filebug?field.title=$BUG_TITLE&field.tags=manpages"
+			RENDER_BUG_LINK="https://github.com/canonical/ubuntu-manpages-operator/issues/new?title=$RENDER_BUG_TITLE&body=<!--#echo var='manpages_site' encoding='url' -->$RENDER_BUG_PATH"
+			# Metadata for link previews in chat tools and social platforms. The
+			# title is already HTML-escaped by w3m, quotes and SSI specials are
+			# dropped so it can be echoed into attributes as is.
+			OG_TITLE="$PAGE - Ubuntu $DIST"
+			OG_DESCRIPTION=$(printf "%s" "$TITLE" | tr -d "'\"\\\\\$" | sed 's/^[[:space:]]*//')
+			# nginx fills in the site URL when the page is served.
+			echo "<!--#set var='og_url' value='\$manpages_site/manpages/$DIST/$i.html' -->
+<!--#set var='og_title' value='$OG_TITLE' -->
+<!--#set var='og_description' value='$OG_DESCRIPTION' -->
+<!--#include virtual='/above1.html' -->
 $TITLE
 <!--#include virtual='/above2.html' -->
-Provided by: <a href='$PKG_LINK'>$NAME_AND_VER</a> <a href='$BUG_LINK' title='Report a bug in the content of this documentation'><img src='/assets/img/bug.png' alt='bug' border=0></a><br><br><pre>
+Provided by: <a href='$PKG_LINK'>$NAME_AND_VER</a> <a href='$BUG_LINK' title='Report a bug in the content of this documentation'><img src='/assets/img/bug.png' alt='bug' border=0></a> <small><a href='$RENDER_BUG_LINK' title='Report a problem with how this page is displayed'>Report a rendering issue</a></small><br><br><pre>
 $BODY
 </pre><!--#include virtual='/below.html' -->" >"$out"
 
@@ -129,6 +172,4 @@ done
 # repeat the downloads
 sha1sum "$DEB" | awk '{ print $1 }' >"$DESTDIR/.cache/$NAME"
 
-# In the case of freakish package permissions, fix them on rm failure.
-rm -rf "$TEMPDIR" 2>/dev/null || (chmod -R 700 "$TEMPDIR" && rm -rf "$TEMPDIR") || true
 exit 0
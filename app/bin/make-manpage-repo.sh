@@ -38,6 +38,19 @@ DISTROS="$(jq -r '.releases | keys | join(" ")' "$CONFIG")"
 REPOS="$(jq -r '.repos | join(" ")' "$CONFIG")"
 ARCH="$(jq -r '.arch' "$CONFIG")"
 
+# Check that the converter works on a known page before processing
+# thousands of packages with it, so a broken host fails fast and clearly.
+selftest=$(mktemp "/tmp/XXXXXXX.manpages.selftest.1")
+printf "%s\n" ".TH SELFTEST 1" ".SH NAME" "selftest \\- converter check" >"$selftest"
+# Use the same environment as fetch-man-pages.sh, and do not let a hung
+# converter block the update forever.
+if ! W3MMAN_MAN='man --no-hyphenation' MAN_KEEP_FORMATTING=1 COLUMNS=115 timeout 60 /usr/lib/w3m/cgi-bin/w3mman2html.cgi "local=$selftest" 2>/dev/null | grep -q "converter check"; then
+	rm -f "$selftest"
+	echo "ERROR: Converter self-check failed, check that man-db and w3m are installed and working."
+	exit 1
+fi
+rm -f "$selftest"
+
 # Establish some locking, to keep multiple updates from running
 mkdir -p "$PUBLIC_HTML_DIR/manpages"
 LOCK="$PUBLIC_HTML_DIR/manpages/UPDATE_IN_PROGRESS"
@@ -50,8 +63,22 @@ fi
 trap 'rm -f $LOCK 2>/dev/null || true' EXIT HUP INT QUIT TERM
 date >"$LOCK"
 
+# Sweep temporary files that an interrupted or failed update left behind
+# more than six hours ago.
+find "${TMPDIR:-/tmp}" /tmp -maxdepth 1 \( -name "manpages-fetch-*" -o -name "*.manpages.*.plist" \) -mmin +360 -exec rm -rf {} + 2>/dev/null || true
+
 FORCE="$1"
 
+# Progress of a previous, interrupted, update is no longer relevant.
+rm -f "$PUBLIC_HTML_DIR"/manpages/*/.cache/.progress
+
+# Smoke-run mode: only process this many packages per release (0 for all), to
+# validate a new deployment before committing to a full multi-hour update.
+LIMIT="$(jq -r '.package_limit // 0' "$CONFIG")"
+
+# Update the releases one at a time, newest first, rather than all at once.
+SEQUENTIAL="$(jq -r '.sequential_updates // false' "$CONFIG")"
+
 get_packages_url() {
 	local dist=$1
 	local repo=$2
@@ -162,9 +189,12 @@ handle_series() {
 	# Packages files can list the same source multiple times).
 	declare -A pkg_handled
 	pkg_handled=()
+	mkdir -p "$PUBLIC_HTML_DIR/manpages/$distnopocket/.cache" "$PUBLIC_HTML_DIR/manpages.gz/$distnopocket" || true
+	link_en_locale "$distnopocket"
+	# Fetch all the Packages files of the release first, so that progress and
+	# the time left can be reported for the release as a whole.
+	local plists=()
 	for pocket in "-updates" "-security" ""; do
-		mkdir -p "$PUBLIC_HTML_DIR/manpages/$distnopocket/.cache" "$PUBLIC_HTML_DIR/manpages.gz/$distnopocket" || true
-		link_en_locale "$distnopocket"
 		for repo in $REPOS; do
 			for arch in $ARCH; do
 				file=$(get_packages_url "${dist}${pocket}" "$repo" "$arch")
@@ -176,24 +206,57 @@ handle_series() {
 					awk '{print $2}' |
 					sed 'N;N;N;s/\n/ /g' |
 					sort -u >"${plist}"
-				while read -r binpkg version deb sum; do
-					if dpkg --compare-versions "${version}" gt "${pkg_handled["$binpkg"]}"; then
-						if [[ -n "${pkg_handled[$binpkg]}" ]]; then
-							echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: binpkg: $binpkg ${version} > ${pkg_handled["$binpkg"]} (processing it again)"
-						else
-							echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: First encounter of binpkg: $binpkg ${version} (processing)"
-						fi
-						pkg_handled["$binpkg"]="${version}"
-						handle_deb "$distnopocket" "$deb" "$sum"
-					else
-						echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: binpkg: $binpkg ${version} < ${pkg_handled["$binpkg"]} (not processing)"
-					fi
-				done <"${plist}"
-				rm -f "${plist}"
+				plists+=("$plist")
 			done
 		done
 	done
+	# Packages are handled in name order within each Packages file. Progress
+	# and an estimate of the time left are logged, and kept in a file the
+	# charm reads to report them in its status.
+	local progress total count start elapsed
+	progress="$PUBLIC_HTML_DIR/manpages/$distnopocket/.cache/.progress"
+	total=$(cat "${plists[@]}" | wc -l)
+	# A smoke run stops after the package limit, so only that many are to go.
+	if [ "$LIMIT" -gt 0 ] && [ "$total" -gt "$LIMIT" ]; then
+		total=$LIMIT
+	fi
+	count=0
+	start=$(date +%s)
+	for plist in "${plists[@]}"; do
+		if [ "$LIMIT" -gt 0 ] && [ "${#pkg_handled[@]}" -ge "$LIMIT" ]; then
+			echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: package limit of $LIMIT reached"
+			break
+		fi
+		while read -r binpkg version deb sum; do
+			if [ "$LIMIT" -gt 0 ] && [ "${#pkg_handled[@]}" -ge "$LIMIT" ]; then
+				break
+			fi
+			count=$((count + 1))
+			# Older versions of a package skipped along the way can take a
+			# smoke run past its total, there is no progress to report then.
+			if [ "$count" -le "$total" ] && { [ $((count % 500)) -eq 0 ] || [ "$count" -eq "$total" ]; }; then
+				elapsed=$(($(date +%s) - start))
+				echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: progress: $count/$total, ETA $((elapsed * (total - count) / count))s"
+				printf "%s %s %s\n" "$count" "$total" "$((elapsed * (total - count) / count))" >"$progress"
+			fi
+			if dpkg --compare-versions "${version}" gt "${pkg_handled["$binpkg"]}"; then
+				if [[ -n "${pkg_handled[$binpkg]}" ]]; then
+					echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: binpkg: $binpkg ${version} > ${pkg_handled["$binpkg"]} (processing it again)"
+				else
+					echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: First encounter of binpkg: $binpkg ${version} (processing)"
+				fi
+				pkg_handled["$binpkg"]="${version}"
+				handle_deb "$distnopocket" "$deb" "$sum"
+			else
+				echo "INFO ($(date '+%H:%M:%S.%N')) - ${dist}: binpkg: $binpkg ${version} < ${pkg_handled["$binpkg"]} (not processing)"
+			fi
+		done <"${plist}"
+	done
+	rm -f "${plists[@]}" "$progress"
 
+	# Flush the release to disk once it is complete, so that a host crash
+	# right after an update cannot leave zero-length pages to be served.
+	sync -f "$PUBLIC_HTML_DIR/manpages/$distnopocket" "$PUBLIC_HTML_DIR/manpages.gz/$distnopocket"
 }
 
 # Simple parallelization on the level of releases; they do not overlap
@@ -201,9 +264,16 @@ handle_series() {
 # connection utilized as one can fetch while the other is converting.
 # Furthermore it avoids that issues, or a lot of new content, in one release
 # (e.g. -dev opened) will make the regular update on the others take ages.
-for dist in $DISTROS; do
-	handle_series "${dist}" &
-done
-wait
+if [ "$SEQUENTIAL" = "true" ]; then
+	for dist in $(jq -r '.releases | to_entries | sort_by(.value | split(".") | map(tonumber)) | reverse | map(.key) | join(" ")' "$CONFIG"); do
+		handle_series "${dist}"
+	done
+else
+	for dist in $DISTROS; do
+		handle_series "${dist}" &
+	done
+	wait
+fi
 
 "$DIR/make-sitemaps.sh"
+"$DIR/make-reports.sh"
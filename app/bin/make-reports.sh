@@ -0,0 +1,112 @@
+#!/bin/bash -e
+# Publish reports about the rendered manpages of each release in /reports/,
+# for maintainers and translators rather than for readers.
+
+CONFIG="${MANPAGES_CONFIG_FILE:-/app/www/config.json}"
+if [[ -z "$CONFIG" ]]; then
+	echo "ERROR: Configuration file not found. Please set \$MANPAGES_CONFIG_FILE."
+	exit 1
+fi
+
+PUBLIC_HTML_DIR="$(jq -r '.public_html_dir' "$CONFIG")"
+DISTROS="$(jq -r '.releases | keys | join(" ")' "$CONFIG")"
+
+printf "%s\n" "INFO: Making reports"
+
+# Cross references are rewritten to ../manN/name.N.html when a page is
+# rendered, whether or not that page exists. List those which do not, with
+# the package providing the page they are in, as "package page target". The
+# pages of each package are listed in the same pass, as "package source page"
+# in the file given as second argument.
+broken_links() {
+	(
+		cd "$PUBLIC_HTML_DIR/manpages/$1"
+		# Symlinks, such as the en/ sections or pages aliased to another one,
+		# are not searched themselves, but are valid targets.
+		find . -name "*.html" -xtype f -print
+		echo "--"
+		grep -rIo --include="*.html" -e "+package/[^']*'" -e "+source/[^/]*/+filebug" -e 'href="\.\./man[1-9][^/"]*/[^"]*\.html"' . || true
+	) | awk -v packages="$2" '
+		BEGIN { printf "" > packages }
+		$0 == "--" { pages = 1; next }
+		!pages { exists[$0] = 1; next }
+		{
+			# Page names may contain colons, e.g. File::Spec.3perl.html.
+			i = index($0, ".html:")
+			file = substr($0, 1, i + 4)
+			m = substr($0, i + 6)
+		}
+		m ~ /^\+package\// {
+			if (!(file in pkg)) pkg[file] = substr(m, 10, length(m) - 10)
+			next
+		}
+		m ~ /^\+source\// {
+			if (!(file in src)) {
+				src[file] = substr(m, 9, length(m) - 17)
+				print pkg[file], src[file], substr(file, 3) > packages
+			}
+			next
+		}
+		{
+			target = substr(m, 7, length(m) - 7)
+			dir = file
+			sub(/\/[^\/]*\/[^\/]*$/, "", dir)
+			if (!((dir "/" substr(target, 4)) in exists)) {
+				owner = (file in pkg) ? pkg[file] : "unknown"
+				print owner, substr(file, 3), target
+			}
+		}' | sort -u
+	sort -o "$2" "$2"
+}
+
+# Translated pages live in a directory per language, next to the English
+# sections. Count the pages of each language, then list every page with the
+# languages it is available in, as "page lang...". The pages of each section
+# are counted in the same pass, per language, as "section lang count" in the
+# file given as second argument.
+translations() {
+	(
+		cd "$PUBLIC_HTML_DIR/manpages/$1"
+		find . -name "*.html" -xtype f -print | sed 's|^\./||' | sort
+	) | awk -F/ -v sections="$2" '
+		BEGIN { printf "" > sections }
+		NF != 2 && NF != 3 { next }
+		{
+			page = NF == 2 ? $0 : $2 "/" $3
+			if (!(page in langs)) order[n++] = page
+			section = substr(page, 4, index(page, "/") - 4)
+		}
+		# English first, then the translations in name order.
+		NF == 2 { count["en"]++; langs[page] = " en" langs[page]; bysection[section " en"]++ }
+		NF == 3 { count[$1]++; langs[page] = langs[page] " " $1; bysection[section " " $1]++ }
+		END {
+			for (lang in count) print lang, count[lang] | "sort"
+			close("sort")
+			print ""
+			for (i = 0; i < n; i++) print order[i] langs[order[i]] | "sort"
+			close("sort")
+			for (s in bysection) print s, bysection[s] > sections
+		}'
+	sort -o "$2" "$2"
+}
+
+mkdir -p "$PUBLIC_HTML_DIR/reports"
+for dist in $DISTROS; do
+	[ -d "$PUBLIC_HTML_DIR/manpages/$dist" ] || continue
+	mkdir -p "$PUBLIC_HTML_DIR/reports/$dist"
+	broken_links "$dist" "$PUBLIC_HTML_DIR/reports/$dist/packages.txt.new" >"$PUBLIC_HTML_DIR/reports/$dist/broken-links.txt.new"
+	mv -f "$PUBLIC_HTML_DIR/reports/$dist/broken-links.txt.new" "$PUBLIC_HTML_DIR/reports/$dist/broken-links.txt"
+	mv -f "$PUBLIC_HTML_DIR/reports/$dist/packages.txt.new" "$PUBLIC_HTML_DIR/reports/$dist/packages.txt"
+	translations "$dist" "$PUBLIC_HTML_DIR/reports/$dist/sections.txt.new" >"$PUBLIC_HTML_DIR/reports/$dist/translations.txt.new"
+	mv -f "$PUBLIC_HTML_DIR/reports/$dist/translations.txt.new" "$PUBLIC_HTML_DIR/reports/$dist/translations.txt"
+	mv -f "$PUBLIC_HTML_DIR/reports/$dist/sections.txt.new" "$PUBLIC_HTML_DIR/reports/$dist/sections.txt"
+done
+
+# Drop the reports of releases that are no longer configured.
+for dir in "$PUBLIC_HTML_DIR"/reports/*/; do
+	[ -d "$dir" ] || continue
+	case " $DISTROS " in
+	*" $(basename "$dir") "*) ;;
+	*) rm -rf "$dir" ;;
+	esac
+done
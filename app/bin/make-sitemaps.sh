@@ -35,19 +35,39 @@ fi
 
 PUBLIC_HTML_DIR="$(jq -r '.public_html_dir' "$CONFIG")"
 SITE="$(jq -r '.site' "$CONFIG")"
+NOINDEX="$(jq -r '.noindex_releases // [] | join(" ")' "$CONFIG")"
 
 printf "%s\n" "INFO: Making sitemaps"
 
 (
 	cd "$PUBLIC_HTML_DIR"
-	find manpages/ -type f -name "*.html" | xargs -I {} printf "%s\n" "<url><loc>$SITE/{}</loc></url>" | split -l 50000 - manpages/sitemap_
+	# Releases marked noindex, interim ones by default, stay browsable, but
+	# are left out of the sitemaps so crawlers index the others instead. They are not disallowed in
+	# robots.txt, crawlers would then never see their noindex header and
+	# could still index externally linked pages, without their content.
+	exclude=()
+	for dist in $NOINDEX; do
+		exclude+=(-not -path "manpages/$dist/*")
+	done
+	# Drop the sitemaps of a previous run, there may now be fewer of them.
+	rm -f manpages/sitemap_*.xml
+	find manpages/ -type f -name "*.html" "${exclude[@]}" | xargs -I {} printf "%s\n" "<url><loc>$SITE/{}</loc></url>" | split -l 50000 - manpages/sitemap_
 
-	sitemaps=$(ls manpages/sitemap_??)
-	for i in $sitemaps; do
+	# There are no sitemaps at all when every release is excluded, robots.txt
+	# is still written then.
+	shopt -s nullglob
+	sitemaps=(manpages/sitemap_??)
+	shopt -u nullglob
+	for i in "${sitemaps[@]}"; do
 		echo "<?xml version='1.0' encoding='UTF-8'?>
 <urlset xmlns='http://www.sitemaps.org/schemas/sitemap/0.9'>" >"$i.xml"
 		cat "$i" >>"$i.xml"
 		echo "</urlset>" >>"$i.xml"
 		rm -f "$i"
 	done
+
+	printf "%s\n" "User-agent: *" "Allow: /" >robots.txt
+	for i in "${sitemaps[@]}"; do
+		printf "%s\n" "Sitemap: $SITE/$i.xml" >>robots.txt
+	done
 )
@@ -25,12 +25,14 @@
 # License can be found in /usr/share/common-licenses/GPL-3
 ###############################################################################
 
+import difflib
 import glob
 import json
 import os
 import re
+import sys
 from collections import OrderedDict
-from urllib.parse import parse_qsl
+from urllib.parse import parse_qsl, quote
 
 with open(os.environ.get("MANPAGES_CONFIG_FILE", "/app/www/config.json"), "r") as f:
     config = json.load(f)
@@ -39,6 +41,16 @@ www_root = config['public_html_dir']
 versions = config['releases']
 distros = versions.keys()
 
+# Every path handed out must resolve inside the manpages tree, whatever the
+# query contains ("../", encoded or not) or wherever a symlink points.
+manpages_root = os.path.realpath(f"{www_root}/manpages") + os.sep
+
+
+def manpages_glob(pattern):
+    """Return the paths matching pattern that resolve inside the manpages tree."""
+    return [g for g in glob.glob(pattern) if os.path.realpath(g).startswith(manpages_root)]
+
+
 # Yes, there are a lot of bad variable names in this script but rather
 # than touch nearly every variable in here, I think restructuring the
 # script to use proper functions/methods is better, so turning off this
@@ -72,11 +84,15 @@ descr = [
     "Kernel routines [Non standard]"
 ]
 
+# Order in which sections are listed: commands first, then administration
+# commands and file formats, then libraries and the remaining sections.
+section_order = [1, 8, 5, 3, 2, 4, 6, 7, 9]
+
 t = ""
 if "q" in get:
     t = get["q"]
 x = 1
-y = 9
+y = 10
 extra = ""
 # User might have specified the section
 p = re.compile(r'(.*)\.([1-9])(.*)$')
@@ -85,10 +101,15 @@ if n:
     t = n.group(1)
     x = int(n.group(2))
     y = x + 1
-    extra = n.group(3)
+    extra = re.sub(r'[^a-zA-Z0-9_+-]', '', n.group(3))
 
-p = re.compile(r'[^\.a-zA-Z0-9\/_\:\+@-]')
+p = re.compile(r'[^\.a-zA-Z0-9\/_\:\+@*?-]')
 t = p.sub('', t)
+# Name globs such as "git-*" list a whole command family, but a pattern
+# that is mostly wildcards would list every page of every release.
+p = re.compile(r'[*?]')
+if len(p.sub('', t)) < 2:
+    t = p.sub('', t)
 title_html = "<script>document.forms[0].q.value='" + t + "';</script>"
 
 if "lr" in get:
@@ -102,51 +123,127 @@ if "lr" in get:
 else:
     lr = "en"
 
+# Narrow the search with the filter controls, a section given as part of the
+# query takes precedence over the section filter.
+release = get.get("release", "")
+if release in versions:
+    distros = [release]
+if not n and get.get("section", "") in [str(i) for i in section_order]:
+    x = int(get["section"])
+    y = x + 1
+
+languages = sorted({os.path.basename(g) for g in glob.glob(f"{www_root}/manpages/*/*")
+                    if os.path.isdir(g) and not os.path.basename(g).startswith("man")})
+filter_html = ("<form method='get' action='/cgi-bin/search.py'>"
+               "<input type='hidden' name='q' value='%s'>"
+               "<select name='release'><option value=''>All releases</option>") % t
+for d in versions:
+    filter_html += "<option value='%s'%s>%s (%s)</option>" % (
+        d, " selected" if d == release else "", d, versions[d])
+filter_html += "</select><select name='section'><option value=''>All sections</option>"
+for i in range(1, len(descr)):
+    filter_html += "<option value='%d'%s>(%d) %s</option>" % (
+        i, " selected" if y - x == 1 and i == x else "", i, descr[i])
+filter_html += "</select><select name='lr'>"
+for lang in languages:
+    filter_html += "<option value='%s'%s>%s</option>" % (
+        lang.replace('_', '-'), " selected" if lang == lr else "", lang)
+filter_html += "</select><button type='submit' class='p-button'>Filter</button></form>"
+
 title_html += ("</div></div><div class='p-strip u-no-padding--top'>"
-               "<div class='row'>"
+               "<div class='row'>" + filter_html +
                "<br><table><tr>"
                "<td><table cellspacing=0 cellpadding=5><thead><tr>")
 for d in distros:
     title_html += "<th>%s<br><small>%s</small></th>" % (
         d, versions[d])
 title_html += "<th>Section Description</th></thead></tr>"
-matches = 0
-for i in range(x, y):
-    title_html += "<tr>"
-    for d in distros:
-        color = "lightgrey"
-        path = f"{www_root}/manpages/{d}/{lr}/man{i}/{t}.{i}{extra}*.html"
-        title_html += "<td align=center>"
-        dot = "."
-        for g in glob.glob(path):
-
-            matches += 1
-            dot = ""
-            color = "black"
-
-            href_path = p1.sub('', g.replace(config["public_html_dir"], ""))
-            page = p2.sub('', g)
-            page = p3.sub('', page)
-            page = p4.sub('', page)
-            title_html += '<a href="%s" style="text-decoration:none">' % (
-                href_path)
-            title_html += '%s(%d)</a>, ' % (page, i)
-        title_html = p5.sub('', title_html)
-        title_html += dot + "</td>"
-    title_html += '<td><font color="%s">(%d) - <small>%s</small></td></tr>' % (
-        color, i, descr[i])
+title_head = title_html
+# A page in the exact section asked for, e.g. passwd.1 rather than
+# passwd.1ssl, is not ambiguous.
+exact = f".{x}{extra}.html" if n else ""
+# Fall back to a case-insensitive match so that e.g. LS.1 still finds ls.1,
+# with the links pointing at the canonical casing.
+t_nocase = "".join(f"[{c.lower()}{c.upper()}]" if c.isalpha() else c for c in t)
+attempts = [(t, x, y, extra), (t_nocase, x, y, extra)]
+# Like man(1), look through the other sections when the page does not exist
+# in the requested one.
+if n:
+    attempts += [(t, 1, 10, ""), (t_nocase, 1, 10, "")]
+for name, lo, hi, suffix in attempts:
+    title_html = title_head
+    matches = 0
+    candidates = []
+    for i in [i for i in section_order if lo <= i < hi]:
+        title_html += "<tr>"
+        for r, d in enumerate(distros):
+            color = "lightgrey"
+            path = f"{www_root}/manpages/{d}/{lr}/man{i}/{name}.{i}{suffix}*.html"
+            title_html += "<td align=center>"
+            dot = "."
+            # List the plain section before suffixed variants, e.g. passwd.1
+            # before passwd.1ssl.
+            found = sorted(manpages_glob(path), key=lambda g: (
+                not g.endswith(f".{i}{suffix}.html"), g))
+            for k, g in enumerate(found):
+
+                matches += 1
+                # Rank by preferred section, then newest release, then plain
+                # section before suffixed variants.
+                candidates.append((section_order.index(i), -r, k, g))
+                dot = ""
+                color = "black"
+
+                href_path = p1.sub('', g.replace(config["public_html_dir"], ""))
+                page = p2.sub('', g)
+                page = p3.sub('', page)
+                page = p4.sub('', page)
+                title_html += '<a href="%s" style="text-decoration:none">' % (
+                    href_path)
+                title_html += '%s(%d)</a>, ' % (page, i)
+            title_html = p5.sub('', title_html)
+            title_html += dot + "</td>"
+        title_html += '<td><font color="%s">(%d) - <small>%s</small></td></tr>' % (
+            color, i, descr[i])
+    if matches > 0:
+        break
 title_html += "</table></td></tr></table><br>"
+if exact and any(c[3].endswith(exact) for c in candidates):
+    candidates = [c for c in candidates if c[3].endswith(exact)]
+distinct = {p2.sub('', c[3]) for c in candidates}
 if matches > 0:
-    if "titles" in get and get["titles"] == "404":
-        # If we were sent here by a 404-not-found, and we have at least one
-        # match, redirect the user to the last page in our list
-        html += "<script>location.replace('" + href_path + "');</script>"
-    else:
-        # Otherwise, a normal title search, display the title table
-        html += title_html
+    if "titles" in get and get["titles"] == "404" and len(distinct) == 1:
+        # If we were sent here by a 404-not-found, or a /man/ shortcut, and a
+        # single page matches (in one or more releases), redirect the user to
+        # its newest release. A real redirect, so curl and crawlers follow it.
+        best_href = p1.sub('', min(candidates)[3].replace(www_root, ""))
+        print("Status: 302 Found\nLocation: %s\n" % quote(best_href))
+        sys.exit()
+    # Otherwise, a normal title search, display the title table
+    html += title_html
 else:
     # But if we do not find any matching titles, do a full text search
-    html += "</div></div><section class='p-strip u-no-padding--top'><div class='row'><h2>No matching titles found</h2>"
+    html += "</div></div><section class='p-strip u-no-padding--top'><div class='row'><h2>No matching titles found</h2>" + filter_html
+    # Suggest pages with a close name, e.g. sshd_config(5) for sshd_conf.5.
+    # Every 404 ends up here, so only look at the names sharing the first two
+    # characters of the query, in the newest release.
+    prefix = "".join(f"[{c.lower()}{c.upper()}]" if c.isalpha() else glob.escape(c)
+                     for c in t[:2])
+    pages = {}
+    for d in list(distros)[-1:] if len(t) >= 2 else []:
+        for i in [i for i in section_order if x <= i < y]:
+            # Plain sections sort last so they win over suffixed variants.
+            for g in sorted(manpages_glob(f"{www_root}/manpages/{d}/{lr}/man{i}/{prefix}*.html"),
+                            key=lambda g: g.endswith(f".{i}.html")):
+                page = p3.sub('', p2.sub('', g))
+                pages[(p4.sub('', page), i)] = p1.sub('', g.replace(www_root, ""))
+    names = {name for name, i in pages}
+    close = difflib.get_close_matches(t, names, n=5, cutoff=0.75) if t else []
+    if close:
+        html += "<p>Did you mean: "
+        html += ", ".join('<a href="%s">%s(%d)</a>' % (pages[(name, i)], name, i)
+                          for name in close for i in section_order if (name, i) in pages)
+        html += "</p>"
 
 html += open(f"{www_root}/below.html").read()
 print(html)  # pylint: disable=superfluous-parens
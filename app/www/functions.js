@@ -115,6 +115,41 @@ function renderNav(versions) {
   }
   navigationContainer.innerHTML = navigationOutput;
   highlightNav();
+  eolBanner(versions);
+}
+
+function eolBanner(versions) {
+  var parent = document.getElementById("tableWrapper");
+  var distro = window.location.pathname.split("/")[2];
+  var current = versions.find((v) => v["name"] == distro);
+  var supported = versions.filter((v) => !v["eol"]);
+  var dismissed = "eolBannerDismissed-" + distro;
+  if (!parent || !current || !current["eol"] || supported.length == 0) {
+    return;
+  }
+  if (localStorage.getItem(dismissed)) {
+    return;
+  }
+  // Point at the same page in the newest release that is still supported.
+  var newest = supported[supported.length - 1]["name"];
+  var href = "/manpages/" + newest;
+  if (location.href.match("\.html$")) {
+    href = location.href.replace(/\/manpages\/[^\/]*/, "/manpages/" + newest);
+  }
+  parent.insertAdjacentHTML(
+    "afterbegin",
+    "<div class='p-notification--caution' id='eolBanner'><p class='p-notification__response'>" +
+      "<span class='p-notification__status'>End of life:</span> " +
+      "Ubuntu " + current["number"] + " (" + distro + ") is no longer supported" +
+      (current["eolDate"] ? ", since " + current["eolDate"] : "") + ". " +
+      "<a href='" + href + "'>View this page in " + newest + "</a>.</p>" +
+      "<button class='p-icon--close' aria-label='Close notification' id='eolBannerClose'>Close</button></div>",
+  );
+  // Remember the dismissal, so the banner stays away on this release's pages.
+  document.getElementById("eolBannerClose").addEventListener("click", () => {
+    localStorage.setItem(dismissed, "1");
+    document.getElementById("eolBanner").remove();
+  });
 }
 
 function updateLocalStorage() {
@@ -123,15 +158,22 @@ function updateLocalStorage() {
     .then((data) => {
       // Get the releases from the config file
       var releases = new Map(Object.entries(data.releases));
+      var eol = data.eol_releases || [];
+      var eolDates = data.eol_dates || {};
+      var lts = data.lts_releases || [];
 
       // Mutate releases into the existing format that was statically defined here.
       var versions = Array.from(releases).map(([name, number]) => {
         // Make sure LTS versions have "LTS" appended.
-        let [maj, min] = number.split(".");
-        if (Number(maj) % 2 == 0 && min == "04") {
-          return { name: name, number: number + " LTS" };
+        if (lts.includes(name)) {
+          return {
+            name: name,
+            number: number + " LTS",
+            eol: eol.includes(name),
+            eolDate: eolDates[name],
+          };
         }
-        return { name, number };
+        return { name, number, eol: eol.includes(name), eolDate: eolDates[name] };
       });
       localStorage.setItem("versions", JSON.stringify(versions));
     })
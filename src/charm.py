@@ -10,6 +10,7 @@ from subprocess import CalledProcessError
 
 import ops
 from charms.operator_libs_linux.v0.apt import PackageError, PackageNotFoundError
+from charms.operator_libs_linux.v1.systemd import SystemdError
 from charms.traefik_k8s.v2.ingress import IngressPerAppRequirer as IngressRequirer
 
 from launchpad import LaunchpadClient
@@ -55,12 +56,24 @@ class ManpagesCharm(ops.CharmBase):
         """Update configuration and fetch relevant manpages."""
         self.unit.status = ops.MaintenanceStatus("Updating configuration")
         try:
-            self._manpages.configure(str(self.config["releases"]), self._get_external_url())
+            self._manpages.configure(
+                str(self.config["releases"]),
+                self._get_external_url(),
+                bool(self.config["json-access-log"]),
+                int(self.config["package-limit"]),
+                str(self.config["noindex-releases"]),
+                bool(self.config["sequential-updates"]),
+            )
         except ValueError:
             self.unit.status = ops.BlockedStatus(
                 "Invalid configuration. Check `juju debug-log` for details."
             )
             return
+        except (CalledProcessError, SystemdError):
+            self.unit.status = ops.BlockedStatus(
+                "Failed to configure nginx. Check `juju debug-log` for details."
+            )
+            return
 
         self.unit.status = ops.MaintenanceStatus("Updating manpages")
         try:
@@ -83,15 +96,23 @@ class ManpagesCharm(ops.CharmBase):
 
         self.unit.set_ports(PORT)
 
-        if self._manpages.updating:
-            self.unit.status = ops.MaintenanceStatus("Updating manpages")
-        else:
-            self.unit.status = ops.ActiveStatus()
+        self._set_workload_status()
 
     def _on_update_status(self, event: ops.UpdateStatusEvent):
         """Update status."""
+        self._set_workload_status()
+
+    def _set_workload_status(self):
+        """Set the unit status according to the state of the manpages update."""
         if self._manpages.updating:
-            self.unit.status = ops.MaintenanceStatus("Updating manpages")
+            progress = self._manpages.progress
+            self.unit.status = ops.MaintenanceStatus(
+                f"Updating manpages ({progress})" if progress else "Updating manpages"
+            )
+        elif self._manpages.update_failed:
+            self.unit.status = ops.BlockedStatus(
+                "Failed to update manpages. Check `journalctl -u update-manpages` for details."
+            )
         else:
             self.unit.status = ops.ActiveStatus()
 
@@ -6,7 +6,7 @@
 
 import os
 from abc import ABC
-from typing import Dict, List, Optional
+from typing import Any, Dict, List, Optional
 
 import httplib2
 import launchpadlib as lplib
@@ -24,10 +24,21 @@ class LaunchpadClientBase(ABC):
         """
         return {}
 
+    def release_status(self, releases: List[str]) -> Dict[str, str]:
+        """Return a dictionary that maps release codenames to their Launchpad series status.
+
+        The status is one of Launchpad's series statuses, such as "Supported",
+        "Current Stable Release" or "Obsolete" once a release has reached its end of life.
+        """
+        return {}
+
 
 class LaunchpadClient(LaunchpadClientBase):
     """Launchpad client implementation."""
 
+    def __init__(self):
+        self._ubuntu_series = None
+
     def release_map(self, releases: List[str]) -> Dict[str, str]:
         """Return a dictionary that maps release codenames to their corresponding versions.
 
@@ -35,25 +46,39 @@ class LaunchpadClient(LaunchpadClientBase):
         returned dictionary is sorted in descending order by version.
         """
         release_map = {}
-
-        lp = Launchpad.login_anonymously(
-            "manpages",
-            lplib.uris.LPNET_SERVICE_ROOT,  # ty: ignore[unresolved-attribute]
-            proxy_info=_proxy_config,
-        )
-
         for release in releases:
-            try:
-                vfilter = filter(lambda x: x.name == release, lp.projects["ubuntu"].series)
-                version = next(vfilter).version
-            except StopIteration:
-                raise ValueError(f"release '{release}' not found on Launchpad")
-
-            release_map[release] = version
+            release_map[release] = self._find_series(release).version
 
         # Return the release map, sorted in descending order by version.
         return dict(sorted(release_map.items(), key=lambda item: item[1]))
 
+    def release_status(self, releases: List[str]) -> Dict[str, str]:
+        """Return a dictionary that maps release codenames to their Launchpad series status.
+
+        The status is one of Launchpad's series statuses, such as "Supported",
+        "Current Stable Release" or "Obsolete" once a release has reached its end of life.
+        """
+        return {release: self._find_series(release).status for release in releases}
+
+    def _find_series(self, release: str) -> Any:
+        """Return the Launchpad series object for the specified release codename.
+
+        The Ubuntu series are fetched once, on first use, and shared between lookups.
+        """
+        if self._ubuntu_series is None:
+            lp = Launchpad.login_anonymously(
+                "manpages",
+                lplib.uris.LPNET_SERVICE_ROOT,  # ty: ignore[unresolved-attribute]
+                proxy_info=_proxy_config,
+            )
+            self._ubuntu_series = {s.name: s for s in lp.projects["ubuntu"].series}
+
+        series = self._ubuntu_series.get(release)
+        if series is None:
+            raise ValueError(f"release '{release}' not found on Launchpad")
+
+        return series
+
 
 class MockLaunchpadClient(LaunchpadClientBase):
     """Mock Launchpad client implementation."""
@@ -83,6 +108,30 @@ class MockLaunchpadClient(LaunchpadClientBase):
         # Return the release map, sorted in descending order by version.
         return dict(sorted(release_map.items(), key=lambda item: item[1]))
 
+    def release_status(self, releases: List[str]) -> Dict[str, str]:
+        """Return a dictionary that maps release codenames to their Launchpad series status.
+
+        The status is one of Launchpad's series statuses, such as "Supported",
+        "Current Stable Release" or "Obsolete" once a release has reached its end of life.
+        """
+        known_releases = {
+            "jammy": "Supported",
+            "noble": "Supported",
+            "oracular": "Obsolete",
+            "plucky": "Obsolete",
+            "questing": "Current Stable Release",
+        }
+
+        release_status = {}
+        for release in releases:
+            status = known_releases.get(release)
+            if status is None:
+                raise ValueError(f"release '{release}' not found on Launchpad")
+
+            release_status[release] = status
+
+        return release_status
+
 
 def _proxy_config(method="https") -> Optional[httplib2.ProxyInfo]:
     """Get charm proxy information from juju charm environment."""
@@ -3,6 +3,7 @@
 
 """Representation of the manpages service."""
 
+import csv
 import json
 import logging
 import os
@@ -10,11 +11,17 @@ import re
 import shutil
 from dataclasses import asdict, dataclass, field
 from pathlib import Path
-from subprocess import CalledProcessError
+from subprocess import CalledProcessError, run
 
 import charms.operator_libs_linux.v0.apt as apt
 from charms.operator_libs_linux.v0.apt import PackageError, PackageNotFoundError
-from charms.operator_libs_linux.v1.systemd import service_restart, service_running
+from charms.operator_libs_linux.v1.systemd import (
+    SystemdError,
+    service_failed,
+    service_reload,
+    service_restart,
+    service_running,
+)
 from jinja2 import Environment, FileSystemLoader
 
 logger = logging.getLogger(__name__)
@@ -33,8 +40,11 @@ CONFIG_PATH = WWW_DIR / "config.json"
 UPDATE_SERVICE_PATH = Path("/etc/systemd/system/update-manpages.service")
 NGINX_SITE_CONFIG_PATH = Path("/etc/nginx/conf.d/manpages.conf")
 
+# Release dates and end of life dates of every Ubuntu release, from distro-info-data.
+DISTRO_INFO_PATH = Path("/usr/share/distro-info/ubuntu.csv")
+
 # Packages installed as part of the update process.
-PACKAGES = ["nginx-full", "fcgiwrap", "jq", "curl", "w3m"]
+PACKAGES = ["nginx-full", "fcgiwrap", "jq", "curl", "w3m", "distro-info-data"]
 
 
 @dataclass
@@ -56,6 +66,12 @@ class ManpagesConfig:
     )
     repos: list = field(default_factory=lambda: ["main", "restricted", "universe", "multiverse"])
     arch: str = "amd64"
+    lts_releases: list = field(default_factory=list)
+    noindex_releases: list = field(default_factory=list)
+    eol_releases: list = field(default_factory=list)
+    eol_dates: dict = field(default_factory=dict)
+    package_limit: int = 0
+    sequential_updates: bool = False
 
 
 class Manpages:
@@ -92,8 +108,7 @@ class Manpages:
         shutil.copytree(source_path / "bin", BIN_DIR, dirs_exist_ok=True)
 
         # Install configuration files
-        config_path = source_path / "config"
-        shutil.copy(config_path / "manpages.conf", NGINX_SITE_CONFIG_PATH)
+        self._template_nginx_config(ManpagesConfig(releases={}), False)
         self._template_systemd_unit()
 
         # Remove default nginx configuration
@@ -109,10 +124,20 @@ class Manpages:
                 except FileNotFoundError:
                     logger.debug("failed to change ownership of '%s'", path)
 
-    def configure(self, releases: str, url: str):
+    def configure(
+        self,
+        releases: str,
+        url: str,
+        json_access_log: bool = False,
+        package_limit: int = 0,
+        noindex_releases: str = "interim",
+        sequential_updates: bool = False,
+    ):
         """Configure the manpages service."""
         try:
-            config = self._build_config(releases, url)
+            config = self._build_config(
+                releases, url, package_limit, noindex_releases, sequential_updates
+            )
         except ValueError as e:
             logger.error("failed to build manpages configuration: invalid releases spec: %s", e)
             raise
@@ -120,10 +145,31 @@ class Manpages:
         # Ensure the systemd unit is updated in case the Juju proxy config has changed.
         self._template_systemd_unit()
 
-        # Write the configuration file for the application.
+        # Update the release alias redirects for the configured releases, keeping
+        # the running configuration if nginx does not accept the new one.
+        previous = NGINX_SITE_CONFIG_PATH.read_text() if NGINX_SITE_CONFIG_PATH.exists() else None
+        self._template_nginx_config(config, json_access_log)
+        try:
+            run(["nginx", "-t"], check=True, capture_output=True, text=True)
+        except CalledProcessError as e:
+            logger.error("failed to validate nginx configuration: %s", e.stderr)
+            if previous is None:
+                NGINX_SITE_CONFIG_PATH.unlink(missing_ok=True)
+            else:
+                NGINX_SITE_CONFIG_PATH.write_text(previous)
+            raise
+
+        # Write the configuration file for the application, only once nginx has
+        # accepted the matching site so both describe the same releases.
         with open(CONFIG_PATH, "w") as f:
             json.dump(asdict(config), f)
 
+        try:
+            service_reload("nginx")
+        except SystemdError as e:
+            logger.error("failed to reload nginx: %s", e)
+            raise
+
     def restart(self):
         """Restart the manpages services."""
         try:
@@ -164,7 +210,31 @@ class Manpages:
         """Report whether the manpages are currently being updated."""
         return service_running("update-manpages")
 
-    def _build_config(self, releases: str, url: str) -> ManpagesConfig:
+    @property
+    def progress(self) -> str:
+        """Report the progress and time left of an ongoing update, for each release."""
+        progress = []
+        for path in sorted((WWW_DIR / "manpages").glob("*/.cache/.progress")):
+            try:
+                count, total, eta = path.read_text().split()
+            except (OSError, ValueError):
+                continue
+            progress.append(f"{path.parent.parent.name} {count}/{total}, ETA {int(eta) // 60}m")
+        return "; ".join(progress)
+
+    @property
+    def update_failed(self) -> bool:
+        """Report whether the last update of the manpages failed."""
+        return service_failed("update-manpages")
+
+    def _build_config(
+        self,
+        releases: str,
+        url: str,
+        package_limit: int = 0,
+        noindex_releases: str = "interim",
+        sequential_updates: bool = False,
+    ) -> ManpagesConfig:
         """Build a ManpagesConfig object using a set of specified release codenames."""
         releases_list = RELEASES_PATTERN.findall(releases)
         if not releases_list:
@@ -172,16 +242,53 @@ class Manpages:
 
         config = ManpagesConfig()
         config.site = url
+        config.package_limit = package_limit
+        config.sequential_updates = sequential_updates
 
-        # Get the release map for the specified release codenames.
+        # Get the release map for the specified release codenames, and note which
+        # of them have reached their end of life so the pages can say so.
         try:
             config.releases = self.launchpad_client.release_map(releases_list)
+            status = self.launchpad_client.release_status(releases_list)
         except ValueError as e:
             logger.error("failed to build manpages config: %s", e)
             raise ValueError(f"failed to build manpages config: {e}")
 
+        # Releases marked noindex remain browsable, but crawlers are pointed at the
+        # others, by default the LTS content.
+        config.lts_releases = [r for r, v in config.releases.items() if _is_lts(v)]
+        noindex_list = RELEASES_PATTERN.findall(noindex_releases)
+        for r in noindex_list:
+            if r != "interim" and r not in config.releases:
+                raise ValueError(f"failed to build manpages config: noindex release '{r}' unknown")
+        config.noindex_releases = [
+            r
+            for r in config.releases
+            if r in noindex_list or ("interim" in noindex_list and r not in config.lts_releases)
+        ]
+        config.eol_releases = [r for r in config.releases if status.get(r) == "Obsolete"]
+        config.eol_dates = _eol_dates(list(config.releases))
+
         return config
 
+    def _template_nginx_config(self, config: ManpagesConfig, json_access_log: bool):
+        """Template out the nginx site configuration, including release alias redirects."""
+        releases, lts_releases = config.releases, config.lts_releases
+        env = Environment(loader=FileSystemLoader(Path(__file__).parent.parent / "app" / "config"))
+        template = env.get_template("manpages.conf.j2")
+        # Releases are sorted by version, so the newest of each kind comes last.
+        context = {
+            "releases": releases,
+            "latest": list(releases)[-1] if releases else "",
+            "lts": lts_releases[-1] if lts_releases else "",
+            "noindex_releases": config.noindex_releases,
+            "site": config.site,
+            "json_access_log": json_access_log,
+        }
+
+        with open(NGINX_SITE_CONFIG_PATH, "w") as f:
+            f.write(template.render(context))
+
     def _template_systemd_unit(self):
         """Template out systemd unit file including proxy variables."""
         # Maps Juju specific proxy environment variables to system equivalents.
@@ -209,3 +316,25 @@ class Manpages:
 
         with open(UPDATE_SERVICE_PATH, "w") as f:
             f.write(template.render(context))
+
+
+def _eol_dates(releases: list) -> dict:
+    """Return the end of life date of each release that distro-info-data knows of.
+
+    Launchpad has the support status of a release, but not the date its
+    support ends.
+    """
+    try:
+        with open(DISTRO_INFO_PATH, newline="") as f:
+            rows = list(csv.DictReader(f))
+    except OSError as e:
+        logger.warning("failed to read release end of life dates: %s", e)
+        return {}
+
+    return {r["series"]: r["eol"] for r in rows if r["series"] in releases and r.get("eol")}
+
+
+def _is_lts(version: str) -> bool:
+    """Report whether a release version number is an LTS release."""
+    major, minor = version.split(".")
+    return int(major) % 2 == 0 and minor == "04"
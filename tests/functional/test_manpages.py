@@ -93,12 +93,37 @@ def test_configure_manpages(manpages):
         "plucky": "25.04",
         "questing": "25.10",
     }
+    cfg.lts_releases = ["jammy", "noble"]
+    cfg.noindex_releases = ["oracular", "plucky", "questing"]
     cfg.site = "http://foo.bar"
 
     with open(CONFIG_PATH, "r") as f:
         content = json.load(f)
+        # More releases reach their end of life over time, so only check
+        # those that are known to have.
+        assert {"oracular", "plucky"} <= set(content["eol_releases"])
+        assert not {"jammy", "noble"} & set(content["eol_releases"])
+        cfg.eol_releases = content["eol_releases"]
+        assert content["eol_dates"]["noble"] == "2029-05-31"
+        cfg.eol_dates = content["eol_dates"]
         assert content == asdict(cfg)
 
+    # Ensure the release aliases are redirected to the configured releases.
+    nginx_config = NGINX_SITE_CONFIG_PATH.read_text()
+    assert r"rewrite ^/manpages/24\.04(/.*)?$ /manpages/noble$1 permanent;" in nginx_config
+    assert "rewrite ^/manpages/latest(/.*)?$ /manpages/questing$1 redirect;" in nginx_config
+    assert "rewrite ^/manpages/lts(/.*)?$ /manpages/noble$1 redirect;" in nginx_config
+    assert '~^/manpages/plucky/ "noindex";' in nginx_config
+    assert '~^/manpages/noble/ "noindex";' not in nginx_config
+
+    # Pages get the site URL from nginx when they are served.
+    assert 'set $manpages_site "http://foo.bar";' in nginx_config
+
+    # The JSON access log is opt-in, the default access log is kept otherwise,
+    # with the request ID appended.
+    assert "manpages.access.log" not in nginx_config
+    assert "access_log /var/log/nginx/access.log manpages_combined;" in nginx_config
+
 
 def test_configure_manpages_bad_codename(manpages):
     releases = "foobar, plucky, oracular, noble, jammy"
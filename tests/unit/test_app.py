@@ -0,0 +1,260 @@
+# Copyright 2025 Canonical
+# See LICENSE file for licensing details.
+
+"""Unit tests for the web application and its maintenance scripts.
+
+These tests run the search script and shell helpers against a temporary
+tree, and do not attempt to manipulate the underlying machine.
+"""
+
+import json
+import os
+import subprocess
+import sys
+from pathlib import Path
+
+import pytest
+
+APP_PATH = Path(__file__).parent.parent.parent / "app"
+
+
+@pytest.fixture
+def www(tmp_path):
+    """Build a minimal web root with two releases and an outside secret page."""
+    www = tmp_path / "www"
+    man1 = www / "manpages" / "noble" / "en" / "man1"
+    man1.mkdir(parents=True)
+    (www / "manpages" / "jammy" / "en" / "man1").mkdir(parents=True)
+    for name in ["above1.html", "above2.html", "below.html"]:
+        (www / name).write_text("")
+    (man1 / "ls.1.html").write_text("ls")
+
+    # Pages that must never be handed out: one outside the manpages tree, and
+    # one inside it that is a symlink pointing out of the tree.
+    (www / "secret.1.html").write_text("secret")
+    outside = tmp_path / "outside"
+    outside.mkdir()
+    (outside / "evil.1.html").write_text("evil")
+    (man1 / "evil.1.html").symlink_to(outside / "evil.1.html")
+
+    config = tmp_path / "config.json"
+    releases = {"jammy": "22.04", "noble": "24.04"}
+    config.write_text(json.dumps({"public_html_dir": str(www), "releases": releases}))
+    return config
+
+
+def add_pages(config: Path, *pages: str):
+    """Create empty pages, given relative to the manpages tree of the web root."""
+    for page in pages:
+        path = config.parent / "www" / "manpages" / page
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text("")
+
+
+def search(config: Path, query: str) -> str:
+    """Run the search script with the specified query string, and return its output."""
+    env = dict(os.environ, MANPAGES_CONFIG_FILE=str(config), QUERY_STRING=query)
+    result = subprocess.run(
+        [sys.executable, str(APP_PATH / "www" / "cgi-bin" / "search.py")],
+        env=env,
+        capture_output=True,
+        text=True,
+        check=True,
+    )
+    return result.stdout
+
+
+def test_search_finds_page(www):
+    assert "/manpages/noble/en/man1/ls.1.html" in search(www, "q=ls.1")
+
+
+def test_search_section_order(www):
+    add_pages(www, "noble/en/man1/passwd.1.html", "noble/en/man5/passwd.5.html")
+    add_pages(www, "noble/en/man8/passwd.8.html")
+    output = search(www, "q=passwd")
+    # Sections are listed by preference: commands, administration, formats.
+    assert (
+        output.index("man1/passwd.1.html")
+        < output.index("man8/passwd.8.html")
+        < output.index("man5/passwd.5.html")
+    )
+
+
+def test_search_glob(www):
+    add_pages(www, "noble/en/man1/git-add.1.html", "noble/en/man1/git-commit.1.html")
+    output = search(www, "q=git-*")
+    assert "/manpages/noble/en/man1/git-add.1.html" in output
+    assert "/manpages/noble/en/man1/git-commit.1.html" in output
+    assert "/manpages/noble/en/man1/ls.1.html" not in output
+
+
+def test_search_wildcards_only(www):
+    assert "/manpages/noble/en/man1/ls.1.html" not in search(www, "q=*")
+
+
+def test_search_case_insensitive(www):
+    output = search(www, "q=LS.1&titles=404")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/noble/en/man1/ls.1.html\n")
+
+
+def test_search_section_fallback(www):
+    add_pages(www, "noble/en/man8/foo.8.html")
+    output = search(www, "q=foo.1&titles=404")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/noble/en/man8/foo.8.html\n")
+
+
+def test_search_section_fallback_kernel(www):
+    add_pages(www, "noble/en/man9/kfoo.9.html")
+    output = search(www, "q=kfoo.1&titles=404")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/noble/en/man9/kfoo.9.html\n")
+
+
+def test_search_newest_release(www):
+    add_pages(www, "jammy/en/man1/ls.1.html")
+    output = search(www, "q=ls.1&titles=404")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/noble/en/man1/ls.1.html\n")
+
+
+# not_found.html passes on the release and language of the missing page.
+def test_search_404_keeps_release(www):
+    add_pages(www, "jammy/en/man1/ls.1.html")
+    output = search(www, "titles=404&release=jammy&lr=lang_en&q=LS.1")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/jammy/en/man1/ls.1.html\n")
+
+
+def test_search_404_section_fallback_keeps_release(www):
+    add_pages(www, "jammy/en/man8/foo.8.html", "noble/en/man8/foo.8.html")
+    output = search(www, "titles=404&release=jammy&lr=lang_en&q=foo.1")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/jammy/en/man8/foo.8.html\n")
+
+
+def test_search_404_keeps_language(www):
+    add_pages(www, "noble/fr/man1/ls.1.html")
+    output = search(www, "titles=404&release=noble&lr=lang_fr&q=LS.1")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/noble/fr/man1/ls.1.html\n")
+
+
+def test_search_suggestions(www):
+    add_pages(www, "noble/en/man5/sshd_config.5.html")
+    output = search(www, "q=sshd_conf.5")
+    assert "No matching titles found" in output
+    suggestion = '<a href="/manpages/noble/en/man5/sshd_config.5.html">sshd_config(5)</a>'
+    assert "Did you mean: " + suggestion in output
+
+
+def test_search_release_filter(www):
+    add_pages(www, "jammy/en/man1/ls.1.html")
+    output = search(www, "q=ls&release=jammy")
+    assert "/manpages/jammy/en/man1/ls.1.html" in output
+    assert "/manpages/noble/en/man1/ls.1.html" not in output
+
+
+def test_search_section_filter(www):
+    add_pages(www, "noble/en/man1/passwd.1.html", "noble/en/man5/passwd.5.html")
+    output = search(www, "q=passwd&section=5")
+    assert "/manpages/noble/en/man5/passwd.5.html" in output
+    assert "/manpages/noble/en/man1/passwd.1.html" not in output
+
+
+def test_search_404_redirects_to_exact_section(www):
+    add_pages(www, "jammy/en/man1/bar.1.html", "noble/en/man1/bar.1posix.html")
+    output = search(www, "q=bar.1&titles=404")
+    assert output.startswith("Status: 302 Found\nLocation: /manpages/jammy/en/man1/bar.1.html\n")
+
+
+def test_search_404_lists_ambiguous_matches(www):
+    add_pages(www, "noble/en/man1/bar.1.html", "noble/en/man1/bar.1posix.html")
+    output = search(www, "q=bar&titles=404")
+    assert "Status: 302" not in output
+    assert "/manpages/noble/en/man1/bar.1.html" in output
+    assert "/manpages/noble/en/man1/bar.1posix.html" in output
+
+
+@pytest.mark.parametrize(
+    "query",
+    [
+        "q=../../../../secret.1",
+        "q=%2e%2e%2f%2e%2e%2f%2e%2e%2f%2e%2e%2fsecret.1",
+        "q=..%2F..%2F..%2F..%2Fsecret.1&titles=404",
+    ],
+)
+def test_search_path_traversal(www, query):
+    output = search(www, query)
+    assert "secret.1.html" not in output
+    assert "No matching titles found" in output
+
+
+def test_search_symlink_out_of_tree(www):
+    output = search(www, "q=evil.1")
+    assert "evil.1.html" not in output
+    assert "No matching titles found" in output
+
+
+@pytest.mark.parametrize(
+    "link,target,expected",
+    [
+        ("man1/ls.1.html", "../man8/ls.8.html", True),
+        ("man1/ls.1.html", "ls.1ssl.html", True),
+        ("man1/ls.1.html", "/etc/passwd", False),
+        ("man1/ls.1.html", "../../outside.html", False),
+        ("man1/ls.1.html", "../../noble-evil/ls.1.html", False),
+    ],
+)
+def test_is_safe_link(tmp_path, link, target, expected):
+    root = tmp_path / "noble"
+    result = subprocess.run(
+        ["bash", "-c", 'source "$0"; is_safe_link "$@"', str(APP_PATH / "bin" / "common.sh")]
+        + [str(root / link), target, str(root)],
+    )
+    assert (result.returncode == 0) == expected
+
+
+@pytest.mark.parametrize("noindex,sitemaps", [([], 1), (["jammy", "noble"], 0)])
+def test_make_sitemaps(www, noindex, sitemaps):
+    config = json.loads(www.read_text())
+    config.update(site="https://manpages.ubuntu.com", noindex_releases=noindex)
+    www.write_text(json.dumps(config))
+    env = dict(os.environ, MANPAGES_CONFIG_FILE=str(www))
+    subprocess.run([APP_PATH / "bin" / "make-sitemaps.sh"], env=env, check=True)
+
+    root = www.parent / "www"
+    robots = (root / "robots.txt").read_text()
+    assert robots.startswith("User-agent: *\nAllow: /\n")
+    assert robots.count("Sitemap: ") == sitemaps
+    assert len(list((root / "manpages").glob("sitemap_*.xml"))) == sitemaps
+
+
+def test_make_reports_broken_links(www):
+    root = www.parent / "www" / "manpages" / "noble" / "en"
+    (root / "man5").mkdir()
+    (root / "man5" / "dir_colors.5.html").write_text("")
+    (root / "man1" / "ls.1.html").write_text(
+        "Provided by: <a href='https://launchpad.net/ubuntu/noble/+package/coreutils'>"
+        "coreutils_9.4-2ubuntu2_amd64</a> "
+        "<a href='https://bugs.launchpad.net/ubuntu/+source/coreutils/+filebug'>bug</a>\n"
+        '<a href="../man5/dir_colors.5.html">dir_colors</a>, '
+        '<a href="../man5/missing.5.html">missing</a>\n'
+    )
+    env = dict(os.environ, MANPAGES_CONFIG_FILE=str(www))
+    subprocess.run([APP_PATH / "bin" / "make-reports.sh"], env=env, check=True)
+
+    report = www.parent / "www" / "reports" / "noble" / "broken-links.txt"
+    assert report.read_text() == "coreutils en/man1/ls.1.html ../man5/missing.5.html\n"
+
+    packages = www.parent / "www" / "reports" / "noble" / "packages.txt"
+    assert packages.read_text() == "coreutils coreutils en/man1/ls.1.html\n"
+
+
+def test_make_reports_translations(www):
+    add_pages(www, "jammy/man1/ls.1.html", "jammy/fr/man1/ls.1.html", "jammy/fr/man1/only.1.html")
+    env = dict(os.environ, MANPAGES_CONFIG_FILE=str(www))
+    subprocess.run([APP_PATH / "bin" / "make-reports.sh"], env=env, check=True)
+
+    report = www.parent / "www" / "reports" / "jammy" / "translations.txt"
+    counts, pages = report.read_text().split("\n\n")
+    assert counts.splitlines() == ["en 1", "fr 2"]
+    assert "man1/ls.1.html en fr" in pages.splitlines()
+    assert "man1/only.1.html fr" in pages.splitlines()
+
+    sections = www.parent / "www" / "reports" / "jammy" / "sections.txt"
+    assert sections.read_text() == "1 en 1\n1 fr 2\n"
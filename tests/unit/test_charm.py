@@ -12,6 +12,7 @@ from unittest.mock import PropertyMock, patch
 
 import pytest
 from charms.operator_libs_linux.v0.apt import PackageError, PackageNotFoundError
+from charms.operator_libs_linux.v1.systemd import SystemdError
 from ops.testing import (
     ActiveStatus,
     Address,
@@ -105,6 +106,16 @@ def test_config_changed_failed_bad_config(configure_mock, ctx, base_state):
     )
 
 
+@patch("charm.Manpages.configure")
+@pytest.mark.parametrize("exception", [CalledProcessError(1, "nginx"), SystemdError("foo")])
+def test_config_changed_failed_nginx(configure_mock, exception, ctx, base_state):
+    configure_mock.side_effect = exception
+    out = ctx.run(ctx.on.config_changed(), base_state)
+    assert out.unit_status == BlockedStatus(
+        "Failed to configure nginx. Check `juju debug-log` for details."
+    )
+
+
 @patch("charm.Manpages.configure")
 @patch("charm.Manpages.update_manpages")
 def test_config_changed_failed_bad_update(update_manpages_mock, configure_mock, ctx, base_state):
@@ -116,8 +127,12 @@ def test_config_changed_failed_bad_update(update_manpages_mock, configure_mock,
     )
 
 
+@patch("charm.Manpages.update_failed", new_callable=PropertyMock)
+@patch("charm.Manpages.updating", new_callable=PropertyMock)
 @patch("charm.Manpages.restart")
-def test_start_success(restart_mock, ctx, base_state):
+def test_start_success(restart_mock, updating_mock, update_failed_mock, ctx, base_state):
+    updating_mock.return_value = False
+    update_failed_mock.return_value = False
     out = ctx.run(ctx.on.start(), base_state)
     assert out.unit_status == ActiveStatus()
     assert restart_mock.called
@@ -135,22 +150,47 @@ def test_start_failure(mock, exception, ctx, base_state):
     assert out.opened_ports == frozenset()
 
 
+@patch("charm.Manpages.progress", new_callable=PropertyMock)
 @patch("charm.Manpages.updating", new_callable=PropertyMock)
-def test_update_status_updating(updating_mock, ctx, base_state):
+def test_update_status_updating(updating_mock, progress_mock, ctx, base_state):
     updating_mock.return_value = True
+    progress_mock.return_value = ""
     out = ctx.run(ctx.on.update_status(), base_state)
     assert out.unit_status == MaintenanceStatus("Updating manpages")
     assert updating_mock.called
 
 
+@patch("charm.Manpages.progress", new_callable=PropertyMock)
 @patch("charm.Manpages.updating", new_callable=PropertyMock)
-def test_update_status_idle(updating_mock, ctx, base_state):
+def test_update_status_updating_progress(updating_mock, progress_mock, ctx, base_state):
+    updating_mock.return_value = True
+    progress_mock.return_value = "noble 500/1000, ETA 5m"
+    out = ctx.run(ctx.on.update_status(), base_state)
+    assert out.unit_status == MaintenanceStatus("Updating manpages (noble 500/1000, ETA 5m)")
+
+
+@patch("charm.Manpages.update_failed", new_callable=PropertyMock)
+@patch("charm.Manpages.updating", new_callable=PropertyMock)
+def test_update_status_idle(updating_mock, update_failed_mock, ctx, base_state):
     updating_mock.return_value = False
+    update_failed_mock.return_value = False
     out = ctx.run(ctx.on.update_status(), base_state)
     assert out.unit_status == ActiveStatus()
     assert updating_mock.called
 
 
+@patch("charm.Manpages.update_failed", new_callable=PropertyMock)
+@patch("charm.Manpages.updating", new_callable=PropertyMock)
+def test_update_status_update_failed(updating_mock, update_failed_mock, ctx, base_state):
+    updating_mock.return_value = False
+    update_failed_mock.return_value = True
+    out = ctx.run(ctx.on.update_status(), base_state)
+    assert out.unit_status == BlockedStatus(
+        "Failed to update manpages. Check `journalctl -u update-manpages` for details."
+    )
+    assert update_failed_mock.called
+
+
 @patch("charm.Manpages.configure")
 @patch("charm.Manpages.update_manpages")
 def test_ingress_no_ingress_workload_url(update_manpages_mock, configure_mock):
@@ -159,7 +199,7 @@ def test_ingress_no_ingress_workload_url(update_manpages_mock, configure_mock):
     state = State(config={"releases": "noble"})
     ctx.run(ctx.on.config_changed(), state)
 
-    configure_mock.assert_called_with("noble", "http://192.0.2.0:8080")
+    configure_mock.assert_called_with("noble", "http://192.0.2.0:8080", False, 0, "interim", False)
 
 
 @patch("charm.Manpages.configure")
@@ -173,7 +213,9 @@ def test_ingress_relation_no_ingress_juju_info_binding(update_manpages_mock, con
     )
     ctx.run(ctx.on.config_changed(), state)
 
-    configure_mock.assert_called_with("noble", "http://10.10.10.10:8080")
+    configure_mock.assert_called_with(
+        "noble", "http://10.10.10.10:8080", False, 0, "interim", False
+    )
 
 
 @patch("charm.Manpages.configure")
@@ -200,5 +242,51 @@ def test_ingress_relation_updates_workload_config(update_manpages_mock, configur
     ctx.run(ctx.on.config_changed(), state)
 
     configure_mock.assert_called_with(
-        "noble", "https://manpages.internal/testing-ubuntu-manpages/"
+        "noble", "https://manpages.internal/testing-ubuntu-manpages/", False, 0, "interim", False
+    )
+
+
+@patch("charm.Manpages.configure")
+@patch("charm.Manpages.update_manpages")
+def test_config_changed_json_access_log(update_manpages_mock, configure_mock):
+    ctx = Context(ManpagesCharm)
+
+    state = State(config={"releases": "noble", "json-access-log": True})
+    ctx.run(ctx.on.config_changed(), state)
+
+    configure_mock.assert_called_with("noble", "http://192.0.2.0:8080", True, 0, "interim", False)
+
+
+@patch("charm.Manpages.configure")
+@patch("charm.Manpages.update_manpages")
+def test_config_changed_package_limit(update_manpages_mock, configure_mock):
+    ctx = Context(ManpagesCharm)
+
+    state = State(config={"releases": "noble", "package-limit": 10})
+    ctx.run(ctx.on.config_changed(), state)
+
+    configure_mock.assert_called_with(
+        "noble", "http://192.0.2.0:8080", False, 10, "interim", False
     )
+
+
+@patch("charm.Manpages.configure")
+@patch("charm.Manpages.update_manpages")
+def test_config_changed_noindex_releases(update_manpages_mock, configure_mock):
+    ctx = Context(ManpagesCharm)
+
+    state = State(config={"releases": "noble", "noindex-releases": ""})
+    ctx.run(ctx.on.config_changed(), state)
+
+    configure_mock.assert_called_with("noble", "http://192.0.2.0:8080", False, 0, "", False)
+
+
+@patch("charm.Manpages.configure")
+@patch("charm.Manpages.update_manpages")
+def test_config_changed_sequential_updates(update_manpages_mock, configure_mock):
+    ctx = Context(ManpagesCharm)
+
+    state = State(config={"releases": "noble", "sequential-updates": True})
+    ctx.run(ctx.on.config_changed(), state)
+
+    configure_mock.assert_called_with("noble", "http://192.0.2.0:8080", False, 0, "interim", True)
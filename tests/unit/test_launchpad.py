@@ -41,6 +41,22 @@ def test_release_map_invalid_release(lp):
         assert str(e) == "release 'foobar' not found on Launchpad"
 
 
+def test_release_status_success(lp):
+    releases = ["questing", "plucky", "noble"]
+    expected = {
+        "questing": "Current Stable Release",
+        "plucky": "Obsolete",
+        "noble": "Supported",
+    }
+    result = lp.release_status(releases)
+    assert result == expected
+
+
+def test_release_status_invalid_release(lp):
+    with pytest.raises(ValueError, match="release 'foobar' not found on Launchpad"):
+        lp.release_status(["foobar", "noble"])
+
+
 @pytest.mark.parametrize(
     "env_var",
     [
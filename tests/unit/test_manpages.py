@@ -7,17 +7,30 @@ These tests only cover those methods that do not require internet access,
 and do not attempt to manipulate the underlying machine.
 """
 
+from subprocess import CalledProcessError
+
 import pytest
 
-from launchpad import MockLaunchpadClient
+import manpages as manpages_module
+from launchpad import LaunchpadClientBase, MockLaunchpadClient
 from manpages import (
     Manpages,
     ManpagesConfig,
+    _is_lts,
 )
 
 
+DISTRO_INFO = """version,codename,series,created,release,eol,eol-server
+24.04 LTS,Noble Numbat,noble,2023-10-12,2024-04-25,2029-05-31,2029-05-31
+25.04,Plucky Puffin,plucky,2024-10-10,2025-04-17,2026-01-15
+"""
+
+
 @pytest.fixture
-def manpages():
+def manpages(tmp_path, monkeypatch):
+    distro_info = tmp_path / "ubuntu.csv"
+    distro_info.write_text(DISTRO_INFO)
+    monkeypatch.setattr(manpages_module, "DISTRO_INFO_PATH", distro_info)
     lp = MockLaunchpadClient()
     return Manpages(lp)
 
@@ -31,6 +44,10 @@ def test_build_config_successful(manpages):
         "plucky": "25.04",
         "questing": "25.10",
     }
+    cfg.lts_releases = ["jammy", "noble"]
+    cfg.noindex_releases = ["oracular", "plucky", "questing"]
+    cfg.eol_releases = ["oracular", "plucky"]
+    cfg.eol_dates = {"noble": "2029-05-31", "plucky": "2026-01-15"}
 
     config = manpages._build_config(
         "questing, plucky, oracular, noble, jammy", "http://manpages.ubuntu.com"
@@ -38,6 +55,52 @@ def test_build_config_successful(manpages):
     assert config == cfg
 
 
+def test_build_config_package_limit(manpages):
+    config = manpages._build_config("noble", "http://manpages.ubuntu.com", 10)
+    assert config.package_limit == 10
+
+
+def test_build_config_sequential_updates(manpages):
+    config = manpages._build_config("noble", "http://manpages.ubuntu.com", 0, "interim", True)
+    assert config.sequential_updates
+
+
+@pytest.mark.parametrize(
+    "noindex,expected",
+    [
+        ("interim", ["oracular", "questing"]),
+        ("interim, jammy", ["jammy", "oracular", "questing"]),
+        ("noble", ["noble"]),
+        ("", []),
+    ],
+)
+def test_build_config_noindex_releases(manpages, noindex, expected):
+    config = manpages._build_config(
+        "questing, oracular, noble, jammy", "http://manpages.ubuntu.com", 0, noindex
+    )
+    assert config.noindex_releases == expected
+
+
+def test_build_config_unknown_noindex_release(manpages):
+    with pytest.raises(ValueError, match="noindex release 'plucky' unknown"):
+        manpages._build_config("noble, jammy", "http://manpages.ubuntu.com", 0, "plucky")
+
+
+def test_build_config_without_release_status():
+    class Client(LaunchpadClientBase):
+        def release_map(self, releases):
+            return {"noble": "24.04"}
+
+    config = Manpages(Client())._build_config("noble", "http://manpages.ubuntu.com")
+    assert config.eol_releases == []
+
+
+def test_build_config_without_distro_info(manpages, tmp_path, monkeypatch):
+    monkeypatch.setattr(manpages_module, "DISTRO_INFO_PATH", tmp_path / "missing.csv")
+    config = manpages._build_config("noble", "http://manpages.ubuntu.com")
+    assert config.eol_dates == {}
+
+
 def test_build_config_unknown_release(manpages):
     try:
         manpages._build_config(
@@ -54,3 +117,65 @@ def test_build_config_invalid_release(manpages):
     except Exception as e:
         assert isinstance(e, ValueError)
         assert str(e) == "failed to build manpages config: invalid releases specified"
+
+
+@pytest.mark.parametrize(
+    "version,expected",
+    [
+        ("22.04", True),
+        ("24.04", True),
+        ("24.10", False),
+        ("25.04", False),
+        ("25.10", False),
+    ],
+)
+def test_is_lts(version, expected):
+    assert _is_lts(version) == expected
+
+
+@pytest.fixture
+def paths(tmp_path, monkeypatch):
+    monkeypatch.setattr(manpages_module, "CONFIG_PATH", tmp_path / "config.json")
+    monkeypatch.setattr(manpages_module, "NGINX_SITE_CONFIG_PATH", tmp_path / "manpages.conf")
+    monkeypatch.setattr(manpages_module, "UPDATE_SERVICE_PATH", tmp_path / "update.service")
+    return tmp_path
+
+
+def nginx_rejects(*args, **kwargs):
+    raise CalledProcessError(1, args[0], stderr="nginx: configuration test failed")
+
+
+def test_configure_invalid_nginx_restores_site(manpages, paths, monkeypatch):
+    monkeypatch.setattr(manpages_module, "run", nginx_rejects)
+    (paths / "manpages.conf").write_text("previous")
+
+    with pytest.raises(CalledProcessError):
+        manpages.configure("noble", "http://manpages.ubuntu.com")
+
+    assert (paths / "manpages.conf").read_text() == "previous"
+    assert not (paths / "config.json").exists()
+
+
+def test_configure_invalid_nginx_removes_new_site(manpages, paths, monkeypatch):
+    monkeypatch.setattr(manpages_module, "run", nginx_rejects)
+
+    with pytest.raises(CalledProcessError):
+        manpages.configure("noble", "http://manpages.ubuntu.com")
+
+    assert not (paths / "manpages.conf").exists()
+    assert not (paths / "config.json").exists()
+
+
+def test_progress(manpages, tmp_path, monkeypatch):
+    monkeypatch.setattr(manpages_module, "WWW_DIR", tmp_path)
+    for release, progress in [("noble", "500 1000 300"), ("jammy", "100 800 4200")]:
+        cache = tmp_path / "manpages" / release / ".cache"
+        cache.mkdir(parents=True)
+        (cache / ".progress").write_text(progress)
+
+    assert manpages.progress == "jammy 100/800, ETA 70m; noble 500/1000, ETA 5m"
+
+
+def test_progress_idle(manpages, tmp_path, monkeypatch):
+    monkeypatch.setattr(manpages_module, "WWW_DIR", tmp_path)
+    assert manpages.progress == ""